@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/SippChat/Sipp/pkg/protocol"
+	"github.com/SippChat/Sipp/pkg/transport"
+	"github.com/SippChat/Sipp/pkg/wire"
+)
+
+const defaultIdentity = ""
+
+func main() {
+	serverPtr := flag.String("server", "localhost:42069", "Server address in the format host:port")
+	identityPath := flag.String("identity", defaultIdentity, "Path to an Ed25519 identity key (generated on first use if given); empty connects anonymously")
+	flag.Parse()
+
+	conn, err := net.Dial("tcp", *serverPtr)
+	if err != nil {
+		log.Fatalf("Failed to connect to server: %v", err)
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("Enter your name: ")
+	name, _ := reader.ReadString('\n')
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Guest"
+	}
+
+	var identity ed25519.PrivateKey
+	if *identityPath != "" {
+		identity, err = transport.LoadOrGenerateIdentity(*identityPath)
+		if err != nil {
+			log.Fatalf("Failed to load identity: %v", err)
+		}
+	}
+
+	sealed, err := handshake(conn, name, identity)
+	if err != nil {
+		log.Fatalf("Handshake failed: %v", err)
+	}
+
+	go receiveMessages(sealed)
+
+	for {
+		fmt.Print("> ")
+		msg, _ := reader.ReadString('\n')
+		msg = strings.TrimSpace(msg)
+		if msg == "" {
+			continue
+		}
+
+		if msg == "/quit" {
+			fmt.Println("Disconnecting...")
+			return
+		}
+
+		if err := sealed.WriteJSON(protocol.Message{Sender: name, Content: msg}); err != nil {
+			fmt.Printf("Failed to send message: %v\n", err)
+		}
+	}
+}
+
+// handshake sends the plaintext HandshakeReq, waits for the server's
+// acceptance, then upgrades conn into an encrypted transport.Conn. Everything
+// here runs over a single wire.Session, so no bytes buffered during the
+// plaintext exchange can be lost when the key exchange begins reading from
+// the same connection.
+func handshake(conn net.Conn, name string, identity ed25519.PrivateKey) (*transport.Conn, error) {
+	session := wire.NewSession(conn)
+
+	req, err := json.Marshal(protocol.HandshakeReq{Magic: protocol.Magic, Client: name})
+	if err != nil {
+		return nil, fmt.Errorf("marshalling handshake: %w", err)
+	}
+	if err := session.WriteLine(string(req) + "\n"); err != nil {
+		return nil, fmt.Errorf("sending handshake: %w", err)
+	}
+
+	raw, err := session.ReadLine()
+	if err != nil {
+		return nil, fmt.Errorf("reading handshake response: %w", err)
+	}
+
+	var res protocol.HandshakeRes
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		return nil, fmt.Errorf("parsing handshake response: %w", err)
+	}
+	if !res.Success {
+		return nil, fmt.Errorf("server rejected handshake: %s", res.Message)
+	}
+
+	var hostFingerprint string
+	sealed, err := transport.ClientHandshake(session, identity, &hostFingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("key exchange: %w", err)
+	}
+	fmt.Printf("Connected. Server host key fingerprint: %s\n", hostFingerprint)
+
+	return sealed, nil
+}
+
+func receiveMessages(conn *transport.Conn) {
+	for {
+		var msg protocol.Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			fmt.Println("Disconnected from server.")
+			return
+		}
+		fmt.Printf("%s: %s\n", msg.Sender, msg.Content)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dispatchCommand handles a native client's slash-command, if text is one,
+// and reports back to user directly (never through messageQueue). It
+// returns false for ordinary chat text, which the caller should then route
+// as a normal channel message.
+func dispatchCommand(user *nativeUser, text string) bool {
+	if !strings.HasPrefix(text, "/") {
+		return false
+	}
+
+	fields := strings.Fields(text)
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "/join":
+		cmdJoin(user, args)
+	case "/part":
+		cmdPart(user, args)
+	case "/list":
+		cmdList(user)
+	case "/topic":
+		cmdTopic(user, args)
+	case "/msg":
+		cmdMsg(user, args)
+	case "/who":
+		cmdWho(user, args)
+	default:
+		reply(user, fmt.Sprintf("Unknown command: %s", cmd))
+	}
+
+	return true
+}
+
+// reply sends a server-originated system message straight to user.
+func reply(user *nativeUser, content string) {
+	if err := user.Deliver(Message{Sender: serverName, Content: content}); err != nil {
+		log.Errorf("Error sending command reply to %s: %v", user.id, err)
+	}
+}
+
+func cmdJoin(user *nativeUser, args []string) {
+	if len(args) == 0 {
+		reply(user, "Usage: /join #channel")
+		return
+	}
+	name := args[0]
+	joinRoom(user, name)
+	user.currentRoom = name
+	reply(user, fmt.Sprintf("Joined %s", name))
+}
+
+func cmdPart(user *nativeUser, args []string) {
+	name := user.currentRoom
+	if len(args) > 0 {
+		name = args[0]
+	}
+	if r, ok := rooms.Get(name); ok {
+		r.Part(user.id)
+	}
+	if user.currentRoom == name {
+		user.currentRoom = defaultRoom
+	}
+	reply(user, fmt.Sprintf("Left %s", name))
+}
+
+func cmdList(user *nativeUser) {
+	var b strings.Builder
+	b.WriteString("Rooms:")
+	for _, r := range rooms.List() {
+		fmt.Fprintf(&b, " %s(%d)", r.Name, r.MemberCount())
+	}
+	reply(user, b.String())
+}
+
+func cmdTopic(user *nativeUser, args []string) {
+	r, ok := rooms.Get(user.currentRoom)
+	if !ok {
+		reply(user, "You're not in a room")
+		return
+	}
+	if len(args) == 0 {
+		reply(user, fmt.Sprintf("Topic for %s: %s", r.Name, r.GetTopic()))
+		return
+	}
+	r.SetTopic(strings.Join(args, " "))
+	reply(user, fmt.Sprintf("Topic for %s set", r.Name))
+}
+
+func cmdMsg(user *nativeUser, args []string) {
+	if len(args) < 2 {
+		reply(user, "Usage: /msg user message")
+		return
+	}
+	messageQueue <- Message{
+		Sender:   user.id,
+		Receiver: args[0],
+		Content:  strings.Join(args[1:], " "),
+	}
+}
+
+func cmdWho(user *nativeUser, args []string) {
+	name := user.currentRoom
+	if len(args) > 0 {
+		name = args[0]
+	}
+	r, ok := rooms.Get(name)
+	if !ok {
+		reply(user, fmt.Sprintf("No such room: %s", name))
+		return
+	}
+	reply(user, fmt.Sprintf("In %s: %s", name, strings.Join(r.MemberIDs(), ", ")))
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/SippChat/Sipp/pkg/irc"
+)
+
+const serverName = "sipp"
+
+// ircUser is a client bridged in over the IRC-compatible listener.
+type ircUser struct {
+	nick   string
+	conn   net.Conn
+	writer *bufio.Writer
+	out    *outbox
+}
+
+func (u *ircUser) ID() string { return u.nick }
+
+// Deliver enqueues msg on u's outbox; the drain goroutine started in
+// handleIRCConn does the actual (deadlined) wire write.
+func (u *ircUser) Deliver(msg Message) error {
+	return u.out.enqueue(msg)
+}
+
+// writeNow renders msg as a PRIVMSG from its sender, addressed to the room
+// it belongs to, or to this user directly for a DM (empty Channel), bounded
+// by writeTimeout so a stuck client gets dropped instead of stalling its
+// outbox drain goroutine forever. A failed write closes the connection,
+// which ends handleIRCConn's read loop and unregisters the client.
+func (u *ircUser) writeNow(msg Message) error {
+	target := msg.Channel
+	if target == "" {
+		target = u.nick
+	}
+	u.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	defer u.conn.SetWriteDeadline(time.Time{})
+	if err := u.send(irc.Message{
+		Prefix:  ircPrefix(msg.Sender),
+		Command: "PRIVMSG",
+		Params:  []string{target, msg.Content},
+	}); err != nil {
+		u.conn.Close()
+		return err
+	}
+	return nil
+}
+
+func (u *ircUser) send(msg irc.Message) error {
+	if _, err := u.writer.WriteString(msg.String() + "\r\n"); err != nil {
+		return fmt.Errorf("sending irc message: %w", err)
+	}
+	return u.writer.Flush()
+}
+
+func (u *ircUser) reply(code, target string, params ...string) error {
+	return u.send(irc.Message{
+		Prefix:  serverName,
+		Command: code,
+		Params:  append([]string{target}, params...),
+	})
+}
+
+func ircPrefix(nick string) string {
+	return fmt.Sprintf("%s!%s@%s", nick, nick, serverName)
+}
+
+// startIRCServer listens for RFC 1459 clients and bridges them into the
+// same messageQueue/clients map the native Sipp listener uses.
+func startIRCServer(port int) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		logAndConsole(fmt.Sprintf("Error listening on IRC port: %v", err))
+		return
+	}
+	defer listener.Close()
+
+	logAndConsole(fmt.Sprintf("Sipp IRC bridge listening on port %d", port))
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			logAndConsole(fmt.Sprintf("Error accepting IRC connection: %v", err))
+			continue
+		}
+		go handleIRCConn(conn)
+	}
+}
+
+// handleIRCConn runs the NICK/USER registration dance and then dispatches
+// the supported command subset until the client disconnects.
+func handleIRCConn(conn net.Conn) {
+	defer conn.Close()
+
+	user := &ircUser{conn: conn, writer: bufio.NewWriter(conn)}
+	user.out = newOutbox(user.writeNow)
+	defer user.out.close()
+	parser := irc.NewParser(conn)
+
+	var registered bool
+	for {
+		msg, err := parser.Next()
+		if err != nil {
+			if registered {
+				rooms.LeaveAll(user.nick)
+				removeClient(user.nick)
+			}
+			return
+		}
+
+		switch msg.Command {
+		case "NICK":
+			if len(msg.Params) == 0 {
+				continue
+			}
+			user.nick = msg.Params[0]
+			if !registered && user.nick != "" {
+				registered = true
+				registerIRCUser(user)
+			}
+		case "USER":
+			// Sipp doesn't track username/realname separately; NICK alone is
+			// enough to register once it's set.
+			if !registered && user.nick != "" {
+				registered = true
+				registerIRCUser(user)
+			}
+		case "PING":
+			token := serverName
+			if len(msg.Params) > 0 {
+				token = msg.Params[0]
+			}
+			user.send(irc.Message{Prefix: serverName, Command: "PONG", Params: []string{serverName, token}})
+		case "PONG":
+			// no-op, keepalive ack
+		case "JOIN":
+			handleIRCJoin(user, msg)
+		case "PART":
+			handleIRCPart(user, msg)
+		case "NAMES":
+			channel := defaultRoom
+			if len(msg.Params) > 0 {
+				channel = msg.Params[0]
+			}
+			sendIRCNames(user, channel)
+		case "LIST":
+			sendIRCList(user)
+		case "PRIVMSG", "NOTICE":
+			handleIRCChat(user, msg)
+		case "QUIT":
+			if registered {
+				rooms.LeaveAll(user.nick)
+				removeClient(user.nick)
+			}
+			return
+		}
+	}
+}
+
+func registerIRCUser(user *ircUser) {
+	addClient(user)
+	rooms.GetOrCreate(defaultRoom).Join(user.nick)
+
+	user.reply("001", user.nick, fmt.Sprintf("Welcome to Sipp, %s", user.nick))
+	user.reply("002", user.nick, fmt.Sprintf("Your host is %s", serverName))
+	user.reply("003", user.nick, "This server has no particular creation date")
+	user.reply("004", user.nick, serverName, "sipp-0", "", "")
+	replayHistory(user, defaultRoom)
+
+	if plainMOTD == "" {
+		user.reply("422", user.nick, "MOTD File is missing")
+		return
+	}
+	user.reply("375", user.nick, fmt.Sprintf("- %s Message of the day -", serverName))
+	for _, line := range strings.Split(strings.TrimRight(plainMOTD, "\n"), "\n") {
+		user.reply("372", user.nick, "- "+line)
+	}
+	user.reply("376", user.nick, "End of MOTD command")
+}
+
+func handleIRCJoin(user *ircUser, msg irc.Message) {
+	if len(msg.Params) == 0 {
+		return
+	}
+	channel := msg.Params[0]
+	if !irc.IsChannel(channel) {
+		user.reply("403", user.nick, channel, "No such channel")
+		return
+	}
+
+	r := rooms.GetOrCreate(channel)
+	r.Join(user.nick)
+
+	user.send(irc.Message{Prefix: ircPrefix(user.nick), Command: "JOIN", Params: []string{channel}})
+	if topic := r.GetTopic(); topic != "" {
+		user.reply("332", user.nick, channel, topic)
+	}
+	for _, line := range strings.Split(strings.TrimRight(r.MOTD, "\n"), "\n") {
+		if line != "" {
+			user.send(irc.Message{Prefix: serverName, Command: "NOTICE", Params: []string{channel, line}})
+		}
+	}
+	sendIRCNames(user, channel)
+	replayHistory(user, channel)
+}
+
+func handleIRCPart(user *ircUser, msg irc.Message) {
+	if len(msg.Params) == 0 {
+		return
+	}
+	channel := msg.Params[0]
+	if r, ok := rooms.Get(channel); ok {
+		r.Part(user.nick)
+	}
+	user.send(irc.Message{Prefix: ircPrefix(user.nick), Command: "PART", Params: []string{channel}})
+}
+
+func sendIRCNames(user *ircUser, channel string) {
+	var names []string
+	if r, ok := rooms.Get(channel); ok {
+		names = r.MemberIDs()
+	}
+
+	user.reply("353", user.nick, "=", channel, strings.Join(names, " "))
+	user.reply("366", user.nick, channel, "End of NAMES list")
+}
+
+func sendIRCList(user *ircUser) {
+	for _, r := range rooms.List() {
+		user.reply("322", user.nick, r.Name, fmt.Sprintf("%d", r.MemberCount()), r.GetTopic())
+	}
+	user.reply("323", user.nick, "End of LIST")
+}
+
+func handleIRCChat(user *ircUser, msg irc.Message) {
+	if len(msg.Params) < 2 {
+		return
+	}
+	target, text := msg.Params[0], msg.Params[1]
+
+	out := Message{Sender: user.nick, Content: text}
+	if irc.IsChannel(target) {
+		out.Channel = target
+	} else {
+		out.Receiver = target
+	}
+	messageQueue <- out
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// fanoutMetrics counts what happens to messages handed to an outbox, so
+// operators can see backpressure building before it becomes an outage.
+var fanoutMetrics struct {
+	Queued  uint64
+	Sent    uint64
+	Dropped uint64
+}
+
+// outbox is a per-client buffered queue of outbound messages, drained by a
+// dedicated goroutine that does the actual (potentially slow) wire write.
+// This keeps deliverToRoom and friends from ever blocking on one client's
+// socket: enqueue is non-blocking and a full outbox just drops the message.
+//
+// enqueue and close are serialized by mu so a disconnect racing a broadcast
+// can never send on ch after it's been closed: deliverToRoom snapshots
+// recipients under a read-lock but calls Deliver (and so enqueue) after
+// releasing it, so a concurrent handleConn teardown is always possible.
+type outbox struct {
+	mu     sync.Mutex
+	ch     chan Message
+	closed bool
+}
+
+// newOutbox starts an outbox backed by a channel of outboxSize, draining it
+// by calling deliver for each message. deliver is expected to apply its own
+// write deadline so a stuck connection doesn't pile up forever.
+func newOutbox(deliver func(Message) error) *outbox {
+	ob := &outbox{ch: make(chan Message, outboxSize)}
+	go ob.run(deliver)
+	return ob
+}
+
+// enqueue queues msg for delivery without blocking. If the client isn't
+// draining fast enough, the message is dropped rather than stalling the
+// caller (and, transitively, every other client being fanned out to). Once
+// the outbox has been closed, enqueue reports an error instead of sending.
+func (ob *outbox) enqueue(msg Message) error {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if ob.closed {
+		return fmt.Errorf("outbox closed")
+	}
+
+	select {
+	case ob.ch <- msg:
+		atomic.AddUint64(&fanoutMetrics.Queued, 1)
+		return nil
+	default:
+		atomic.AddUint64(&fanoutMetrics.Dropped, 1)
+		return fmt.Errorf("outbox full, dropping message")
+	}
+}
+
+func (ob *outbox) run(deliver func(Message) error) {
+	for msg := range ob.ch {
+		if err := deliver(msg); err != nil {
+			continue
+		}
+		atomic.AddUint64(&fanoutMetrics.Sent, 1)
+	}
+}
+
+// close stops the drain goroutine. Safe to call concurrently with enqueue
+// (and more than once); an enqueue afterwards reports an error.
+func (ob *outbox) close() {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if ob.closed {
+		return
+	}
+	ob.closed = true
+	close(ob.ch)
+}
+
+// startDebugServer exposes fan-out metrics as JSON on /debug. Disabled when
+// port is 0.
+func startDebugServer(port int) {
+	if port == 0 {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]uint64{
+			"queued":  atomic.LoadUint64(&fanoutMetrics.Queued),
+			"sent":    atomic.LoadUint64(&fanoutMetrics.Sent),
+			"dropped": atomic.LoadUint64(&fanoutMetrics.Dropped),
+		})
+	})
+
+	addr := fmt.Sprintf(":%d", port)
+	logAndConsole(fmt.Sprintf("Debug metrics listening on %s/debug", addr))
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Errorf("Debug server failed: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SippChat/Sipp/pkg/wire"
+)
+
+// scanBufSize bounds bufio.Scanner's token buffer. A history line is a
+// JSON-encoded Message plus a trailing newline, and a Message's Content can
+// be as large as a wire frame's payload (wire.MaxFrameSize), so the default
+// 64 KiB token cap isn't enough once chat messages approach that size.
+const scanBufSize = wire.MaxFrameSize + 4096
+
+// FileStore is a Store backed by one append-only logs/<room>.jsonl file per
+// room, fsync'd on a timer rather than after every write.
+type FileStore struct {
+	dir       string
+	syncEvery time.Duration
+
+	mu    sync.Mutex
+	files map[string]*os.File
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewFileStore returns a FileStore writing room logs under dir, fsync'ing
+// every syncEvery (0 disables the periodic fsync).
+func NewFileStore(dir string, syncEvery time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating history dir: %w", err)
+	}
+
+	fs := &FileStore{
+		dir:       dir,
+		syncEvery: syncEvery,
+		files:     make(map[string]*os.File),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go fs.syncLoop()
+	return fs, nil
+}
+
+// Close stops the fsync loop and closes every open room file.
+func (fs *FileStore) Close() error {
+	close(fs.stop)
+	<-fs.done
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, f := range fs.files {
+		f.Close()
+	}
+	return nil
+}
+
+func (fs *FileStore) syncLoop() {
+	defer close(fs.done)
+	if fs.syncEvery <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(fs.syncEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fs.mu.Lock()
+			for _, f := range fs.files {
+				f.Sync()
+			}
+			fs.mu.Unlock()
+		case <-fs.stop:
+			return
+		}
+	}
+}
+
+// sanitizeRoomName strips a room's leading channel sigil and rejects
+// anything that could escape dir when joined into a filename, e.g. a room
+// named "#../../tmp/x".
+func sanitizeRoomName(room string) (string, error) {
+	name := strings.TrimLeft(room, "#+&!")
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid room name %q", room)
+	}
+	return name, nil
+}
+
+func (fs *FileStore) path(room string) (string, error) {
+	name, err := sanitizeRoomName(room)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(fs.dir, name+".jsonl"), nil
+}
+
+func (fs *FileStore) fileFor(room string) (*os.File, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if f, ok := fs.files[room]; ok {
+		return f, nil
+	}
+
+	path, err := fs.path(room)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening room log: %w", err)
+	}
+	fs.files[room] = f
+	return f, nil
+}
+
+// Append implements Store.
+func (fs *FileStore) Append(msg Message) error {
+	f, err := fs.fileFor(msg.Room)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshalling history entry: %w", err)
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	_, err = f.Write(append(raw, '\n'))
+	return err
+}
+
+func (fs *FileStore) readAll(room string) ([]Message, error) {
+	path, err := fs.path(room)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening room log: %w", err)
+	}
+	defer file.Close()
+
+	var messages []Message
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), scanBufSize)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("parsing history entry: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading room log: %w", err)
+	}
+
+	return messages, nil
+}
+
+// Tail implements Store.
+func (fs *FileStore) Tail(room string, n int) ([]Message, error) {
+	all, err := fs.readAll(room)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && n < len(all) {
+		all = all[len(all)-n:]
+	}
+	return all, nil
+}
+
+// Since implements Store.
+func (fs *FileStore) Since(room string, since time.Time) ([]Message, error) {
+	all, err := fs.readAll(room)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, msg := range all {
+		if msg.Timestamp.After(since) {
+			return all[i:], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,25 @@
+// Package history stores per-room scrollback so a client that (re)joins a
+// room can be replayed the messages it missed, instead of only ever seeing
+// what's sent from the moment it connects.
+package history
+
+import "time"
+
+// Message is one stored line of room history.
+type Message struct {
+	Room      string
+	Sender    string
+	Content   string
+	Timestamp time.Time
+}
+
+// Store persists room history and serves it back out.
+type Store interface {
+	// Append records msg for msg.Room.
+	Append(msg Message) error
+	// Tail returns the last n messages for room, oldest first. n <= 0
+	// returns everything the Store still has.
+	Tail(room string, n int) ([]Message, error)
+	// Since returns every message for room strictly after since, oldest first.
+	Since(room string, since time.Time) ([]Message, error)
+}
@@ -0,0 +1,117 @@
+package history
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultSize is how many messages a room's ring buffer holds when none is
+// specified, mirroring the role of go-micro's DefaultSize constants.
+const DefaultSize = 1024
+
+// RingStore is an in-memory Store backed by a fixed-size ring buffer per
+// room. It's the default backend: fast, but history is lost on restart.
+type RingStore struct {
+	size int
+
+	mu    sync.RWMutex
+	rooms map[string]*ring
+}
+
+// NewRingStore returns a RingStore holding up to size messages per room.
+// size <= 0 uses DefaultSize.
+func NewRingStore(size int) *RingStore {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &RingStore{size: size, rooms: make(map[string]*ring)}
+}
+
+func (s *RingStore) ringFor(room string) *ring {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rooms[room]
+	if !ok {
+		r = newRing(s.size)
+		s.rooms[room] = r
+	}
+	return r
+}
+
+// Append implements Store.
+func (s *RingStore) Append(msg Message) error {
+	s.ringFor(msg.Room).add(msg)
+	return nil
+}
+
+// Tail implements Store.
+func (s *RingStore) Tail(room string, n int) ([]Message, error) {
+	s.mu.RLock()
+	r, ok := s.rooms[room]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+
+	all := r.snapshot()
+	if n > 0 && n < len(all) {
+		all = all[len(all)-n:]
+	}
+	return all, nil
+}
+
+// Since implements Store.
+func (s *RingStore) Since(room string, since time.Time) ([]Message, error) {
+	all, err := s.Tail(room, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, msg := range all {
+		if msg.Timestamp.After(since) {
+			return all[i:], nil
+		}
+	}
+	return nil, nil
+}
+
+// ring is a fixed-capacity circular buffer of Messages.
+type ring struct {
+	mu   sync.RWMutex
+	buf  []Message
+	next int
+	full bool
+}
+
+func newRing(size int) *ring {
+	return &ring{buf: make([]Message, size)}
+}
+
+func (r *ring) add(msg Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.buf[r.next] = msg
+	r.next++
+	if r.next == len(r.buf) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// snapshot returns the buffer's contents in insertion order, oldest first.
+func (r *ring) snapshot() []Message {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.full {
+		out := make([]Message, r.next)
+		copy(out, r.buf[:r.next])
+		return out
+	}
+
+	out := make([]Message, len(r.buf))
+	n := copy(out, r.buf[r.next:])
+	copy(out[n:], r.buf[:r.next])
+	return out
+}
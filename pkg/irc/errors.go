@@ -0,0 +1,8 @@
+package irc
+
+import "errors"
+
+var (
+	errEmptyLine = errors.New("irc: empty line")
+	errNoCommand = errors.New("irc: missing command")
+)
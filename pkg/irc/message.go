@@ -0,0 +1,114 @@
+// Package irc implements just enough of RFC 1459 to let a stock IRC client
+// join a Sipp server: parsing/formatting the wire protocol and a couple of
+// helpers (SplitTarget, IsChannel) that the rest of the message grammar
+// leans on.
+package irc
+
+import "strings"
+
+// Message is a single parsed IRC line: an optional prefix, the command (a
+// name like "PRIVMSG" or a three-digit numeric reply), and its parameters.
+// The final parameter may have been introduced with a leading ':' to allow
+// spaces; that's already stripped by Parse and doesn't need to be restored
+// unless the text itself contains a space (String re-adds it as needed).
+type Message struct {
+	Prefix  string
+	Command string
+	Params  []string
+}
+
+// Parse decodes a single raw IRC line (without the trailing CRLF) into a Message.
+func Parse(line string) (Message, error) {
+	var msg Message
+
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return msg, errEmptyLine
+	}
+
+	if strings.HasPrefix(line, ":") {
+		parts := strings.SplitN(line, " ", 2)
+		msg.Prefix = strings.TrimPrefix(parts[0], ":")
+		if len(parts) < 2 {
+			return msg, errNoCommand
+		}
+		line = parts[1]
+	}
+
+	// Split off the trailing parameter, introduced with " :", before
+	// tokenizing the rest on spaces.
+	var trailing string
+	hasTrailing := false
+	if idx := strings.Index(line, " :"); idx != -1 {
+		trailing = line[idx+2:]
+		hasTrailing = true
+		line = line[:idx]
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return msg, errNoCommand
+	}
+
+	msg.Command = strings.ToUpper(fields[0])
+	if len(fields) > 1 {
+		msg.Params = fields[1:]
+	}
+	if hasTrailing {
+		msg.Params = append(msg.Params, trailing)
+	}
+
+	return msg, nil
+}
+
+// String formats the message back into a wire-ready IRC line (no trailing CRLF).
+func (m Message) String() string {
+	var b strings.Builder
+
+	if m.Prefix != "" {
+		b.WriteString(":")
+		b.WriteString(m.Prefix)
+		b.WriteString(" ")
+	}
+
+	b.WriteString(m.Command)
+
+	for i, param := range m.Params {
+		b.WriteString(" ")
+		last := i == len(m.Params)-1
+		if last && (param == "" || strings.ContainsRune(param, ' ') || strings.HasPrefix(param, ":")) {
+			b.WriteString(":")
+		}
+		b.WriteString(param)
+	}
+
+	return b.String()
+}
+
+// SplitTarget splits a "nick!user@host" prefix into its three parts. Any
+// component not present in prefix comes back empty.
+func SplitTarget(prefix string) (nick, user, host string) {
+	if at := strings.Index(prefix, "@"); at != -1 {
+		host = prefix[at+1:]
+		prefix = prefix[:at]
+	}
+	if bang := strings.Index(prefix, "!"); bang != -1 {
+		user = prefix[bang+1:]
+		prefix = prefix[:bang]
+	}
+	nick = prefix
+	return nick, user, host
+}
+
+// IsChannel reports whether name carries one of the RFC 1459 channel prefixes.
+func IsChannel(name string) bool {
+	if name == "" {
+		return false
+	}
+	switch name[0] {
+	case '#', '+', '&', '!':
+		return true
+	default:
+		return false
+	}
+}
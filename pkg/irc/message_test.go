@@ -0,0 +1,123 @@
+package irc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Message
+	}{
+		{
+			name: "simple command",
+			line: "PING",
+			want: Message{Command: "PING"},
+		},
+		{
+			name: "command with params",
+			line: "NICK alice",
+			want: Message{Command: "NICK", Params: []string{"alice"}},
+		},
+		{
+			name: "prefixed command",
+			line: ":alice!a@host PRIVMSG #sipp :hello there",
+			want: Message{
+				Prefix:  "alice!a@host",
+				Command: "PRIVMSG",
+				Params:  []string{"#sipp", "hello there"},
+			},
+		},
+		{
+			name: "lowercase command is upcased",
+			line: "join #sipp",
+			want: Message{Command: "JOIN", Params: []string{"#sipp"}},
+		},
+		{
+			name: "empty trailing param",
+			line: "PRIVMSG #sipp :",
+			want: Message{Command: "PRIVMSG", Params: []string{"#sipp", ""}},
+		},
+		{
+			name: "CRLF is trimmed",
+			line: "PING\r\n",
+			want: Message{Command: "PING"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want error
+	}{
+		{"empty line", "", errEmptyLine},
+		{"only CRLF", "\r\n", errEmptyLine},
+		{"prefix with no command", ":alice", errNoCommand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.line); err != tt.want {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.line, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTarget(t *testing.T) {
+	tests := []struct {
+		prefix string
+		nick   string
+		user   string
+		host   string
+	}{
+		{"alice!a@host", "alice", "a", "host"},
+		{"alice", "alice", "", ""},
+		{"alice@host", "alice", "", "host"},
+		{"", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		nick, user, host := SplitTarget(tt.prefix)
+		if nick != tt.nick || user != tt.user || host != tt.host {
+			t.Errorf("SplitTarget(%q) = (%q, %q, %q), want (%q, %q, %q)",
+				tt.prefix, nick, user, host, tt.nick, tt.user, tt.host)
+		}
+	}
+}
+
+func TestIsChannel(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"#sipp", true},
+		{"+sipp", true},
+		{"&sipp", true},
+		{"!sipp", true},
+		{"sipp", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsChannel(tt.name); got != tt.want {
+			t.Errorf("IsChannel(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
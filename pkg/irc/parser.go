@@ -0,0 +1,34 @@
+package irc
+
+import (
+	"bufio"
+	"io"
+)
+
+// Parser reads successive IRC lines off a stream and decodes them into Messages.
+type Parser struct {
+	reader *bufio.Reader
+}
+
+// NewParser returns a Parser reading from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{reader: bufio.NewReader(r)}
+}
+
+// Next reads and parses the next non-blank line, silently skipping any
+// blank lines in between (real IRC clients send stray ones, e.g. as
+// keepalives). It returns io.EOF when the underlying stream is exhausted.
+func (p *Parser) Next() (Message, error) {
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil && line == "" {
+			return Message{}, err
+		}
+
+		msg, perr := Parse(line)
+		if perr == errEmptyLine {
+			continue
+		}
+		return msg, perr
+	}
+}
@@ -0,0 +1,39 @@
+package irc
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParserNextSkipsBlankLines(t *testing.T) {
+	p := NewParser(strings.NewReader("\r\nNICK alice\r\n\r\nPING\r\n"))
+
+	msg, err := p.Next()
+	if err != nil {
+		t.Fatalf("Next() returned error: %v", err)
+	}
+	if msg.Command != "NICK" {
+		t.Fatalf("Next() command = %q, want NICK", msg.Command)
+	}
+
+	msg, err = p.Next()
+	if err != nil {
+		t.Fatalf("Next() returned error: %v", err)
+	}
+	if msg.Command != "PING" {
+		t.Fatalf("Next() command = %q, want PING", msg.Command)
+	}
+
+	if _, err := p.Next(); err != io.EOF {
+		t.Fatalf("Next() error = %v, want io.EOF", err)
+	}
+}
+
+func TestParserNextAllBlank(t *testing.T) {
+	p := NewParser(strings.NewReader("\r\n\r\n\r\n"))
+
+	if _, err := p.Next(); err != io.EOF {
+		t.Fatalf("Next() error = %v, want io.EOF", err)
+	}
+}
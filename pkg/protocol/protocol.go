@@ -0,0 +1,31 @@
+// Package protocol defines the plaintext handshake envelope and chat
+// message shape shared by the Sipp client (cmd/client) and server (built
+// from the repo root), so the two binaries agree on the wire format
+// without either importing the other's main package.
+package protocol
+
+// Magic identifies a Sipp client in the plaintext handshake, before the
+// connection is upgraded to an encrypted transport.Conn.
+const Magic = "SippClientHello"
+
+// HandshakeReq is the plaintext request a client sends to open a
+// connection, before the curve25519 key exchange begins.
+type HandshakeReq struct {
+	Magic  string `json:"magic"`
+	Client string `json:"client"`
+}
+
+// HandshakeRes is the server's plaintext reply to a HandshakeReq.
+type HandshakeRes struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// Message is a chat message exchanged over an encrypted Conn, either a
+// broadcast to Channel or a direct message to Receiver.
+type Message struct {
+	Sender   string `json:"sender"`   // Client ID of the sender
+	Receiver string `json:"receiver"` // Client ID of the receiver (can be empty for broadcast)
+	Channel  string `json:"channel"`  // Room the message belongs to (empty for a direct message)
+	Content  string `json:"content"`  // Message content
+}
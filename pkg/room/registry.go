@@ -0,0 +1,98 @@
+package room
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Registry tracks every known Room by name, creating them (and loading
+// their per-room MOTD from motdDir/<room>.txt) on first use.
+type Registry struct {
+	motdDir string
+	mu      sync.RWMutex
+	rooms   map[string]*Room
+}
+
+// NewRegistry returns a Registry that loads per-room MOTDs from motdDir.
+func NewRegistry(motdDir string) *Registry {
+	return &Registry{
+		motdDir: motdDir,
+		rooms:   make(map[string]*Room),
+	}
+}
+
+// Get returns the room named name, if it exists.
+func (reg *Registry) Get(name string) (*Room, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	r, ok := reg.rooms[name]
+	return r, ok
+}
+
+// GetOrCreate returns the room named name, creating it (and loading its
+// MOTD file, if any) the first time it's requested.
+func (reg *Registry) GetOrCreate(name string) *Room {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if r, ok := reg.rooms[name]; ok {
+		return r
+	}
+
+	r := New(name)
+	r.MOTD, _ = loadMOTD(reg.motdDir, name)
+	reg.rooms[name] = r
+	return r
+}
+
+// List returns a snapshot of every known room.
+func (reg *Registry) List() []*Room {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	rooms := make([]*Room, 0, len(reg.rooms))
+	for _, r := range reg.rooms {
+		rooms = append(rooms, r)
+	}
+	return rooms
+}
+
+// LeaveAll removes memberID from every room it's a member of, e.g. on disconnect.
+func (reg *Registry) LeaveAll(memberID string) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, r := range reg.rooms {
+		r.Part(memberID)
+	}
+}
+
+// sanitizeRoomName strips a room's leading channel sigil and rejects
+// anything that could escape dir when joined into a filename, e.g. a room
+// named "#../../tmp/x".
+func sanitizeRoomName(name string) (string, error) {
+	trimmed := strings.TrimLeft(name, "#+&!")
+	if trimmed == "" || strings.ContainsAny(trimmed, `/\`) || strings.Contains(trimmed, "..") {
+		return "", fmt.Errorf("invalid room name %q", name)
+	}
+	return trimmed, nil
+}
+
+// loadMOTD reads dir/<name-without-leading-sigil>.txt. A missing file is not
+// an error; it just means the room has no MOTD.
+func loadMOTD(dir, name string) (string, error) {
+	trimmed, err := sanitizeRoomName(name)
+	if err != nil {
+		return "", nil
+	}
+	path := filepath.Join(dir, trimmed+".txt")
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,79 @@
+// Package room implements the channel/room primitive: named groups of
+// members with a topic and an optional MOTD. Scrollback lives in
+// pkg/history, not here.
+package room
+
+import "sync"
+
+// Room is a named channel clients can join and part. Members and Topic are
+// mutated through the methods below, which take care of locking; MOTD is
+// set once by Registry when it creates the room.
+type Room struct {
+	Name    string
+	Topic   string
+	MOTD    string
+	Members map[string]bool
+
+	mu sync.RWMutex
+}
+
+// New returns an empty Room named name.
+func New(name string) *Room {
+	return &Room{
+		Name:    name,
+		Members: make(map[string]bool),
+	}
+}
+
+// Join adds memberID to the room.
+func (r *Room) Join(memberID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Members[memberID] = true
+}
+
+// Part removes memberID from the room.
+func (r *Room) Part(memberID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.Members, memberID)
+}
+
+// Has reports whether memberID is currently in the room.
+func (r *Room) Has(memberID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Members[memberID]
+}
+
+// MemberIDs returns a snapshot of the room's current members.
+func (r *Room) MemberIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.Members))
+	for id := range r.Members {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// MemberCount returns the number of members currently in the room.
+func (r *Room) MemberCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.Members)
+}
+
+// SetTopic updates the room's topic.
+func (r *Room) SetTopic(topic string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Topic = topic
+}
+
+// GetTopic returns the room's current topic.
+func (r *Room) GetTopic() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Topic
+}
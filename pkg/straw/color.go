@@ -0,0 +1,98 @@
+package straw
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Color is a resolved color, carried on a Format. ansi and index256 record
+// how it was written (a legacy 8-color name, or a bare 256-palette index)
+// so ANSIRenderer can emit the shortest matching SGR sequence; both are -1
+// when the tag used a #rrggbb hex triple.
+type Color struct {
+	R, G, B  uint8
+	ansi     int
+	index256 int
+}
+
+// colorNames are the original 8 ANSI foreground names, usable bare
+// (<red>...</red>, for backward compatibility) or as a value
+// (<color=red>, <bg=red>).
+var colorNames = map[string]Color{
+	"black":   {R: 0, G: 0, B: 0, ansi: 0, index256: -1},
+	"red":     {R: 205, G: 0, B: 0, ansi: 1, index256: -1},
+	"green":   {R: 0, G: 205, B: 0, ansi: 2, index256: -1},
+	"yellow":  {R: 205, G: 205, B: 0, ansi: 3, index256: -1},
+	"blue":    {R: 0, G: 0, B: 238, ansi: 4, index256: -1},
+	"magenta": {R: 205, G: 0, B: 205, ansi: 5, index256: -1},
+	"cyan":    {R: 0, G: 205, B: 205, ansi: 6, index256: -1},
+	"white":   {R: 229, G: 229, B: 229, ansi: 7, index256: -1},
+}
+
+// resolveColor interprets a <color=...>/<bg=...> value as one of the 8
+// named colors, a 256-palette index (0-255), or a #rrggbb hex triple.
+func resolveColor(value string) (Color, error) {
+	if value == "" {
+		return Color{}, fmt.Errorf("straw: empty color value")
+	}
+	if named, ok := colorNames[value]; ok {
+		return named, nil
+	}
+	if strings.HasPrefix(value, "#") {
+		return parseHexColor(value)
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 || n > 255 {
+			return Color{}, fmt.Errorf("straw: 256-color index %d out of range", n)
+		}
+		r, g, b := palette256(n)
+		return Color{R: r, G: g, B: b, ansi: -1, index256: n}, nil
+	}
+	return Color{}, fmt.Errorf("straw: unrecognized color %q", value)
+}
+
+func parseHexColor(value string) (Color, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) != 6 {
+		return Color{}, fmt.Errorf("straw: %q is not a #rrggbb color", value)
+	}
+	raw, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return Color{}, fmt.Errorf("straw: %q is not a #rrggbb color", value)
+	}
+	return Color{
+		R:        uint8(raw >> 16),
+		G:        uint8(raw >> 8),
+		B:        uint8(raw),
+		ansi:     -1,
+		index256: -1,
+	}, nil
+}
+
+// ansi16 is the standard terminal approximation of the xterm 256-color
+// palette's first 16 entries (the base 8 plus their bright variants).
+var ansi16 = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// palette256 converts an xterm 256-color palette index to its RGB
+// approximation: the first 16 are the named ANSI colors, 16-231 are a
+// 6x6x6 color cube, and 232-255 are a 24-step grayscale ramp.
+func palette256(n int) (r, g, b uint8) {
+	switch {
+	case n < 16:
+		c := ansi16[n]
+		return c[0], c[1], c[2]
+	case n < 232:
+		n -= 16
+		cube := [6]uint8{0, 95, 135, 175, 215, 255}
+		return cube[(n/36)%6], cube[(n/6)%6], cube[n%6]
+	default:
+		level := uint8(8 + (n-232)*10)
+		return level, level, level
+	}
+}
@@ -0,0 +1,45 @@
+package straw
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// HTMLRenderer renders tokens as nested <span> elements with inline
+// styles, for a future web client. Unlike ANSIRenderer it doesn't need the
+// full active stack at each tag boundary: HTML nesting restores the outer
+// style on </span> for free.
+type HTMLRenderer struct{}
+
+func (HTMLRenderer) Render(tokens []Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		switch t.Kind {
+		case TokenText:
+			b.WriteString(html.EscapeString(t.Text))
+		case TokenOpen:
+			fmt.Fprintf(&b, `<span style="%s">`, cssFor(t.Format))
+		case TokenClose:
+			b.WriteString("</span>")
+		}
+	}
+	return b.String()
+}
+
+func cssFor(f Format) string {
+	switch f.Tag {
+	case "b":
+		return "font-weight:bold"
+	case "i":
+		return "font-style:italic"
+	case "u":
+		return "text-decoration:underline"
+	case "s":
+		return "text-decoration:line-through"
+	}
+	if f.FG != nil {
+		return fmt.Sprintf("color:#%02x%02x%02x", f.FG.R, f.FG.G, f.FG.B)
+	}
+	return fmt.Sprintf("background-color:#%02x%02x%02x", f.BG.R, f.BG.G, f.BG.B)
+}
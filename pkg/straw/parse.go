@@ -0,0 +1,126 @@
+package straw
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tagRegex matches both bare tags (<b>, </b>, <red>) and valued tags
+// (<color=#ff8800>, <bg=214>); the value group is absent (index -1) for the
+// bare form.
+var tagRegex = regexp.MustCompile(`<(/?)([a-zA-Z]+)(?:=([^>]*))?>`)
+
+// keywordTags are bare style toggles that never take a value.
+var keywordTags = map[string]bool{"b": true, "i": true, "u": true, "s": true}
+
+// Parse turns input's <tag>...</tag> markup into a token stream. Tags must
+// nest like balanced parentheses: a closing tag must match the innermost
+// still-open tag, and every opened tag must eventually be closed. Color
+// values must be a recognized name, a 0-255 palette index, or a #rrggbb hex
+// triple. Anything else is a parse error rather than markup that's silently
+// dropped or passed through.
+func Parse(input string) ([]Token, error) {
+	var tokens []Token
+	var stack []Format
+
+	matches := tagRegex.FindAllStringSubmatchIndex(input, -1)
+	lastEnd := 0
+
+	for _, m := range matches {
+		if m[0] > lastEnd {
+			tokens = append(tokens, Token{Kind: TokenText, Text: input[lastEnd:m[0]]})
+		}
+
+		closing := input[m[2]:m[3]] == "/"
+		name := input[m[4]:m[5]]
+		hasValue := m[6] != -1
+		var value string
+		if hasValue {
+			value = input[m[6]:m[7]]
+		}
+
+		if closing {
+			if hasValue {
+				return nil, fmt.Errorf("straw: closing tag </%s> must not carry a value", name)
+			}
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("straw: unmatched closing tag </%s>", name)
+			}
+			top := stack[len(stack)-1]
+			if top.Tag != name {
+				return nil, fmt.Errorf("straw: mismatched closing tag: expected </%s>, got </%s>", top.Tag, name)
+			}
+			stack = stack[:len(stack)-1]
+			tokens = append(tokens, Token{Kind: TokenClose, Format: top, Stack: snapshot(stack)})
+			lastEnd = m[1]
+			continue
+		}
+
+		format, err := resolveTag(name, value, hasValue)
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, format)
+		tokens = append(tokens, Token{Kind: TokenOpen, Format: format, Stack: snapshot(stack)})
+		lastEnd = m[1]
+	}
+
+	if lastEnd < len(input) {
+		tokens = append(tokens, Token{Kind: TokenText, Text: input[lastEnd:]})
+	}
+
+	if len(stack) > 0 {
+		names := make([]string, len(stack))
+		for i, f := range stack {
+			names[i] = f.Tag
+		}
+		return nil, fmt.Errorf("straw: unclosed tag(s): %s", strings.Join(names, ", "))
+	}
+
+	return tokens, nil
+}
+
+func snapshot(stack []Format) []Format {
+	out := make([]Format, len(stack))
+	copy(out, stack)
+	return out
+}
+
+func resolveTag(name, value string, hasValue bool) (Format, error) {
+	if keywordTags[name] {
+		if hasValue {
+			return Format{}, fmt.Errorf("straw: <%s> doesn't take a value", name)
+		}
+		return Format{Tag: name}, nil
+	}
+
+	switch name {
+	case "color":
+		if !hasValue {
+			return Format{}, fmt.Errorf("straw: <color> requires a value, e.g. <color=red>")
+		}
+		c, err := resolveColor(value)
+		if err != nil {
+			return Format{}, err
+		}
+		return Format{Tag: "color", FG: &c}, nil
+	case "bg":
+		if !hasValue {
+			return Format{}, fmt.Errorf("straw: <bg> requires a value, e.g. <bg=red>")
+		}
+		c, err := resolveColor(value)
+		if err != nil {
+			return Format{}, err
+		}
+		return Format{Tag: "bg", BG: &c}, nil
+	}
+
+	if hasValue {
+		return Format{}, fmt.Errorf("straw: unknown tag <%s=...>", name)
+	}
+	if c, ok := colorNames[name]; ok {
+		return Format{Tag: name, FG: &c}, nil
+	}
+	return Format{}, fmt.Errorf("straw: unknown tag <%s>", name)
+}
@@ -0,0 +1,82 @@
+package straw
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Renderer turns a parsed token stream into a concrete output format.
+type Renderer interface {
+	Render(tokens []Token) string
+}
+
+// ANSIRenderer renders tokens as a terminal escape sequence stream. Every
+// tag boundary re-emits a reset followed by the SGR codes for the full
+// active stack, rather than trying to diff against the previous state, so
+// a closing tag always restores exactly what was active before it opened.
+type ANSIRenderer struct{}
+
+func (ANSIRenderer) Render(tokens []Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		switch t.Kind {
+		case TokenText:
+			b.WriteString(t.Text)
+		case TokenOpen, TokenClose:
+			b.WriteString("\x1b[0m")
+			for _, f := range t.Stack {
+				b.WriteString(sgrFor(f))
+			}
+		}
+	}
+	return b.String()
+}
+
+func sgrFor(f Format) string {
+	switch f.Tag {
+	case "b":
+		return "\x1b[1m"
+	case "i":
+		return "\x1b[3m"
+	case "u":
+		return "\x1b[4m"
+	case "s":
+		return "\x1b[9m"
+	}
+	if f.FG != nil {
+		return ansiColorSeq(30, *f.FG)
+	}
+	if f.BG != nil {
+		return ansiColorSeq(40, *f.BG)
+	}
+	return ""
+}
+
+// ansiColorSeq emits the shortest SGR sequence that represents c: the
+// classic \x1b[3Xm/\x1b[4Xm form for one of the 8 named colors, the
+// extended 256-palette form for a bare index, or 24-bit truecolor
+// otherwise. base is 30 for foreground, 40 for background.
+func ansiColorSeq(base int, c Color) string {
+	if c.ansi >= 0 {
+		return fmt.Sprintf("\x1b[%dm", base+c.ansi)
+	}
+	if c.index256 >= 0 {
+		return fmt.Sprintf("\x1b[%d;5;%dm", base+8, c.index256)
+	}
+	return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", base+8, c.R, c.G, c.B)
+}
+
+// PlainRenderer strips all markup, keeping only the literal text. It's
+// meant for destinations that shouldn't carry escape codes or HTML at all,
+// such as log files.
+type PlainRenderer struct{}
+
+func (PlainRenderer) Render(tokens []Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		if t.Kind == TokenText {
+			b.WriteString(t.Text)
+		}
+	}
+	return b.String()
+}
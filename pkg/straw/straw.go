@@ -1,54 +1,19 @@
+// Package straw implements the chat markup used in messages and MOTDs: tags
+// like <b>, <u>, <color=...> and <bg=...> that nest like balanced
+// parentheses. Parse turns markup into a Token stream, and a Renderer turns
+// that stream into a concrete output: ANSI escapes for a terminal, HTML for
+// a web client, or plain text with the tags stripped for logs.
 package straw
 
-import (
-    "regexp"
-    "strings"
-)
-
-// Define the formatting map
-var formatMap = map[string]string{
-    "black":   "\x1b[30m",
-    "red":     "\x1b[31m",
-    "green":   "\x1b[32m",
-    "yellow":  "\x1b[33m",
-    "blue":    "\x1b[34m",
-    "magenta": "\x1b[35m",
-    "cyan":    "\x1b[36m",
-    "white":   "\x1b[37m",
-    "b":       "\x1b[1m", // Bold
-    "i":       "\x1b[3m", // Italic
-    "u":       "\x1b[4m", // Underline
-    "s":       "\x1b[9m", // Strikethrough
-}
-
-var tagRegex = regexp.MustCompile(`<(/?)([a-zA-Z]+)>`)
-
-// Serialize converts input text with formatting tags to a formatted string.
+// Serialize parses input and renders it for an ANSI terminal. It's the
+// long-standing entry point kept for callers that just want a formatted
+// string; callers that care about malformed markup, or need a different
+// render target, should call Parse and a Renderer directly.
 func Serialize(input string) string {
-    var builder strings.Builder
-
-    matches := tagRegex.FindAllStringSubmatchIndex(input, -1)
-    lastEnd := 0
-
-    for _, match := range matches {
-        isClosing := input[match[2]:match[3]] == "/"
-        tagName := input[match[4]:match[5]]
-
-        builder.WriteString(input[lastEnd:match[0]])
-
-        if format, ok := formatMap[tagName]; ok {
-            if !isClosing {
-                builder.WriteString(format)
-            } else {
-                builder.WriteString("\x1b[0m") // Reset formatting
-            }
-        }
-
-        lastEnd = match[1]
-    }
-
-    builder.WriteString(input[lastEnd:])
-
-    return builder.String()
+	tokens, err := Parse(input)
+	if err != nil {
+		// Malformed markup: show it verbatim rather than drop the message.
+		return input
+	}
+	return ANSIRenderer{}.Render(tokens)
 }
-
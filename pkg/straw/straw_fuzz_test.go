@@ -0,0 +1,47 @@
+package straw
+
+import "testing"
+
+// FuzzParse checks that Parse never panics on arbitrary markup and that,
+// whenever it does accept input, every renderer can consume the resulting
+// token stream without panicking and PlainRenderer reproduces the input's
+// literal text exactly.
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"",
+		"plain text",
+		"<b>bold</b>",
+		"<color=#ff8800>hex</color>",
+		"<color=214>256</color>",
+		"<bg=red>background</bg>",
+		"<b><red>hi <u>there</u></red></b>",
+		"<b>unterminated",
+		"</b>",
+		"<color>missing value</color>",
+		"<color=bogus>bad name</color>",
+		"<color=256>out of range</color>",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens, err := Parse(input)
+		if err != nil {
+			return
+		}
+
+		var want string
+		for _, tok := range tokens {
+			if tok.Kind == TokenText {
+				want += tok.Text
+			}
+		}
+		if got := (PlainRenderer{}).Render(tokens); got != want {
+			t.Fatalf("PlainRenderer changed the literal text: got %q, want %q", got, want)
+		}
+
+		(ANSIRenderer{}).Render(tokens)
+		(HTMLRenderer{}).Render(tokens)
+	})
+}
@@ -0,0 +1,33 @@
+package straw
+
+// TokenKind distinguishes a literal text run from a tag boundary.
+type TokenKind int
+
+const (
+	TokenText TokenKind = iota
+	TokenOpen
+	TokenClose
+)
+
+// Format is one resolved formatting directive pushed onto (or popped off)
+// the parser's nesting stack: a bare keyword style ("b", "i", "u", "s"), a
+// foreground color ("color", or one of the legacy bare color names), or a
+// background color ("bg").
+type Format struct {
+	Tag string // tag name as written: "b", "i", "u", "s", "color", "bg", or a bare color name
+	FG  *Color // set when Tag resolves to a foreground color
+	BG  *Color // set when Tag == "bg"
+}
+
+// Token is one element of a parsed stream: either a run of literal text, or
+// an open/close tag. On a TokenOpen or TokenClose, Stack is the full set of
+// formats active once this token has been applied (outermost first); a
+// TokenText carries no Stack. A TokenClose's Stack is what
+// renderers should restore *to*, so they never need to reconstruct it by
+// diffing against prior tokens themselves.
+type Token struct {
+	Kind   TokenKind
+	Text   string
+	Format Format
+	Stack  []Format
+}
@@ -0,0 +1,237 @@
+package transport
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/SippChat/Sipp/pkg/wire"
+	"golang.org/x/crypto/curve25519"
+)
+
+// kexMessage is exchanged in the clear (before encryption is live) to set
+// up the curve25519 key exchange. The server additionally authenticates
+// itself by signing its ephemeral public key with its long-lived host key.
+type kexMessage struct {
+	KEXPub    string `json:"kex_pub"`
+	HostPub   string `json:"host_pub,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// authMessage follows the kex exchange and lets the client prove ownership
+// of the identity key behind the fingerprint it claims, by signing the
+// server's ephemeral KEX public key (which neither party could have known
+// in advance). IdentityPub is empty for anonymous connections.
+type authMessage struct {
+	IdentityPub string `json:"identity_pub,omitempty"`
+	Signature   string `json:"signature,omitempty"`
+}
+
+func readAuth(session *wire.Session) (authMessage, error) {
+	var msg authMessage
+	raw, err := session.ReadLine()
+	if err != nil {
+		return msg, fmt.Errorf("reading auth: %w", err)
+	}
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		return msg, fmt.Errorf("parsing auth: %w", err)
+	}
+	return msg, nil
+}
+
+func writeAuth(session *wire.Session, msg authMessage) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshalling auth: %w", err)
+	}
+	return session.WriteLine(string(raw) + "\n")
+}
+
+func readKEX(session *wire.Session) (kexMessage, error) {
+	var msg kexMessage
+	raw, err := session.ReadLine()
+	if err != nil {
+		return msg, fmt.Errorf("reading kex: %w", err)
+	}
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		return msg, fmt.Errorf("parsing kex: %w", err)
+	}
+	return msg, nil
+}
+
+func writeKEX(session *wire.Session, msg kexMessage) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshalling kex: %w", err)
+	}
+	return session.WriteLine(string(raw) + "\n")
+}
+
+func sharedSecret(priv, peerPub [32]byte) ([32]byte, error) {
+	var secret [32]byte
+	out, err := curve25519.X25519(priv[:], peerPub[:])
+	if err != nil {
+		return secret, fmt.Errorf("computing shared secret: %w", err)
+	}
+	copy(secret[:], out)
+	return secret, nil
+}
+
+func newEphemeralKeypair() (priv, pub [32]byte, err error) {
+	if _, err = rand.Read(priv[:]); err != nil {
+		return priv, pub, fmt.Errorf("generating ephemeral key: %w", err)
+	}
+	out, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		return priv, pub, fmt.Errorf("deriving ephemeral public key: %w", err)
+	}
+	copy(pub[:], out)
+	return priv, pub, nil
+}
+
+func decode32(s string) ([32]byte, error) {
+	var out [32]byte
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return out, fmt.Errorf("decoding key: %w", err)
+	}
+	if len(raw) != 32 {
+		return out, fmt.Errorf("decoding key: expected 32 bytes, got %d", len(raw))
+	}
+	copy(out[:], raw)
+	return out, nil
+}
+
+// ServerHandshake runs the server side of the curve25519 key exchange over
+// session, authenticating itself with hostKey. The client, if it claims an
+// identity, must prove possession of the matching private key by signing
+// this handshake's server ephemeral public key; only a fingerprint backed
+// by that proof is checked against whitelist (which may be nil) to decide
+// Conn.Admin. The returned Conn shares session's reader/writer, so no bytes
+// buffered during this exchange (or the plaintext handshake before it) can
+// be lost to a second bufio.Reader built on the same connection.
+func ServerHandshake(session *wire.Session, hostKey ed25519.PrivateKey, whitelist *Whitelist) (*Conn, error) {
+	clientKEX, err := readKEX(session)
+	if err != nil {
+		return nil, err
+	}
+
+	clientPub, err := decode32(clientKEX.KEXPub)
+	if err != nil {
+		return nil, err
+	}
+
+	ephPriv, ephPub, err := newEphemeralKeypair()
+	if err != nil {
+		return nil, err
+	}
+
+	signature := ed25519.Sign(hostKey, ephPub[:])
+	if err := writeKEX(session, kexMessage{
+		KEXPub:    base64.StdEncoding.EncodeToString(ephPub[:]),
+		HostPub:   base64.StdEncoding.EncodeToString(hostKey.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}); err != nil {
+		return nil, err
+	}
+
+	clientAuth, err := readAuth(session)
+	if err != nil {
+		return nil, err
+	}
+
+	var fingerprint string
+	if clientAuth.IdentityPub != "" {
+		identityPub, err := base64.StdEncoding.DecodeString(clientAuth.IdentityPub)
+		if err != nil {
+			return nil, fmt.Errorf("decoding client identity key: %w", err)
+		}
+		authSignature, err := base64.StdEncoding.DecodeString(clientAuth.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("decoding client auth signature: %w", err)
+		}
+		if !ed25519.Verify(ed25519.PublicKey(identityPub), ephPub[:], authSignature) {
+			return nil, fmt.Errorf("client identity signature invalid")
+		}
+		fingerprint = Fingerprint(ed25519.PublicKey(identityPub))
+	}
+
+	secret, err := sharedSecret(ephPriv, clientPub)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := newConn(session, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed.Fingerprint = fingerprint
+	sealed.Admin = whitelist.Allowed(fingerprint)
+
+	return sealed, nil
+}
+
+// ClientHandshake runs the client side of the curve25519 key exchange over
+// session. identity is the client's own long-lived Ed25519 key (may be nil
+// for an anonymous connection, in which case no fingerprint is sent). To
+// prove it actually holds identity's private key (and isn't just replaying
+// a public fingerprint), the client signs the server's ephemeral KEX public
+// key, which is fresh for every handshake. hostFingerprint, if non-nil,
+// is filled in with the server's host key fingerprint so the caller can
+// show it to the user for out-of-band verification.
+func ClientHandshake(session *wire.Session, identity ed25519.PrivateKey, hostFingerprint *string) (*Conn, error) {
+	ephPriv, ephPub, err := newEphemeralKeypair()
+	if err != nil {
+		return nil, err
+	}
+
+	req := kexMessage{KEXPub: base64.StdEncoding.EncodeToString(ephPub[:])}
+	if err := writeKEX(session, req); err != nil {
+		return nil, err
+	}
+
+	serverKEX, err := readKEX(session)
+	if err != nil {
+		return nil, err
+	}
+
+	hostPub, err := base64.StdEncoding.DecodeString(serverKEX.HostPub)
+	if err != nil {
+		return nil, fmt.Errorf("decoding host key: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(serverKEX.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("decoding host signature: %w", err)
+	}
+
+	serverPub, err := decode32(serverKEX.KEXPub)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(hostPub), serverPub[:], signature) {
+		return nil, fmt.Errorf("server host key signature invalid")
+	}
+	if hostFingerprint != nil {
+		*hostFingerprint = Fingerprint(ed25519.PublicKey(hostPub))
+	}
+
+	var auth authMessage
+	if identity != nil {
+		auth.IdentityPub = base64.StdEncoding.EncodeToString(identity.Public().(ed25519.PublicKey))
+		auth.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(identity, serverPub[:]))
+	}
+	if err := writeAuth(session, auth); err != nil {
+		return nil, err
+	}
+
+	secret, err := sharedSecret(ephPriv, serverPub)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(session, secret)
+}
@@ -0,0 +1,160 @@
+package transport
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/SippChat/Sipp/pkg/wire"
+)
+
+func pipeSessions() (server, client *wire.Session) {
+	serverConn, clientConn := net.Pipe()
+	return wire.NewSession(serverConn), wire.NewSession(clientConn)
+}
+
+type handshakeResult struct {
+	conn *Conn
+	err  error
+}
+
+func TestHandshakeRoundTrip(t *testing.T) {
+	serverSess, clientSess := pipeSessions()
+
+	_, hostKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating host key: %v", err)
+	}
+
+	serverCh := make(chan handshakeResult, 1)
+	clientCh := make(chan handshakeResult, 1)
+	go func() {
+		conn, err := ServerHandshake(serverSess, hostKey, nil)
+		serverCh <- handshakeResult{conn, err}
+	}()
+	go func() {
+		conn, err := ClientHandshake(clientSess, nil, nil)
+		clientCh <- handshakeResult{conn, err}
+	}()
+
+	sr, cr := <-serverCh, <-clientCh
+	if sr.err != nil {
+		t.Fatalf("ServerHandshake: %v", sr.err)
+	}
+	if cr.err != nil {
+		t.Fatalf("ClientHandshake: %v", cr.err)
+	}
+	if sr.conn.Admin {
+		t.Error("anonymous client was granted admin")
+	}
+
+	// net.Pipe is unbuffered, so the write only completes once the server
+	// side reads it; run it concurrently with ReadJSON.
+	writeErr := make(chan error, 1)
+	go func() {
+		writeErr <- cr.conn.WriteJSON(map[string]string{"hello": "world"})
+	}()
+	var got map[string]string
+	if err := sr.conn.ReadJSON(&got); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	if err := <-writeErr; err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	if got["hello"] != "world" {
+		t.Errorf("got %v, want {hello: world}", got)
+	}
+}
+
+func TestHandshakeGrantsAdminWithProof(t *testing.T) {
+	serverSess, clientSess := pipeSessions()
+
+	_, hostKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating host key: %v", err)
+	}
+	identityPub, identityKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating identity key: %v", err)
+	}
+	whitelist := &Whitelist{fingerprints: map[string]bool{Fingerprint(identityPub): true}}
+
+	serverCh := make(chan handshakeResult, 1)
+	clientCh := make(chan handshakeResult, 1)
+	go func() {
+		conn, err := ServerHandshake(serverSess, hostKey, whitelist)
+		serverCh <- handshakeResult{conn, err}
+	}()
+	go func() {
+		conn, err := ClientHandshake(clientSess, identityKey, nil)
+		clientCh <- handshakeResult{conn, err}
+	}()
+
+	sr, cr := <-serverCh, <-clientCh
+	if sr.err != nil {
+		t.Fatalf("ServerHandshake: %v", sr.err)
+	}
+	if cr.err != nil {
+		t.Fatalf("ClientHandshake: %v", cr.err)
+	}
+	if !sr.conn.Admin {
+		t.Error("client proving its whitelisted identity was not granted admin")
+	}
+	if sr.conn.Fingerprint != Fingerprint(identityPub) {
+		t.Errorf("Fingerprint = %q, want %q", sr.conn.Fingerprint, Fingerprint(identityPub))
+	}
+}
+
+// TestServerHandshakeRejectsSpoofedFingerprint plays the part of a client
+// that knows an admin's public fingerprint (which is not secret) but not
+// their private key, and tries to claim it anyway. ServerHandshake must
+// refuse: a fingerprint alone proves nothing without a signature over this
+// handshake's own ephemeral key.
+func TestServerHandshakeRejectsSpoofedFingerprint(t *testing.T) {
+	serverSess, clientSess := pipeSessions()
+
+	_, hostKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating host key: %v", err)
+	}
+	victimPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating victim key: %v", err)
+	}
+	whitelist := &Whitelist{fingerprints: map[string]bool{Fingerprint(victimPub): true}}
+
+	serverCh := make(chan handshakeResult, 1)
+	go func() {
+		conn, err := ServerHandshake(serverSess, hostKey, whitelist)
+		if err == nil && conn.Admin {
+			err = fmt.Errorf("server granted admin to a spoofed fingerprint")
+		}
+		serverCh <- handshakeResult{conn, err}
+	}()
+
+	// Run the real key exchange (so the handshake otherwise looks
+	// legitimate), then claim the victim's identity with a signature that
+	// could only come from someone who doesn't hold its private key.
+	_, ephPub, err := newEphemeralKeypair()
+	if err != nil {
+		t.Fatalf("newEphemeralKeypair: %v", err)
+	}
+	if err := writeKEX(clientSess, kexMessage{KEXPub: base64.StdEncoding.EncodeToString(ephPub[:])}); err != nil {
+		t.Fatalf("writeKEX: %v", err)
+	}
+	if _, err := readKEX(clientSess); err != nil {
+		t.Fatalf("readKEX: %v", err)
+	}
+	if err := writeAuth(clientSess, authMessage{
+		IdentityPub: base64.StdEncoding.EncodeToString(victimPub),
+		Signature:   base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize)),
+	}); err != nil {
+		t.Fatalf("writeAuth: %v", err)
+	}
+
+	if res := <-serverCh; res.err == nil {
+		t.Fatal("ServerHandshake accepted a forged identity signature")
+	}
+}
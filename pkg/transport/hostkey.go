@@ -0,0 +1,44 @@
+package transport
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+const identityBlockType = "SIPP IDENTITY KEY"
+
+// LoadOrGenerateIdentity loads an Ed25519 identity key from path, generating
+// and persisting a new one if it doesn't exist yet. It backs both the
+// server's host key (--identity on sipp-server) and a client's optional
+// identity key (--identity on the client).
+func LoadOrGenerateIdentity(path string) (ed25519.PrivateKey, error) {
+	if raw, err := os.ReadFile(path); err == nil {
+		block, _ := pem.Decode(raw)
+		if block == nil || block.Type != identityBlockType {
+			return nil, fmt.Errorf("parsing identity key %s: not a %s block", path, identityBlockType)
+		}
+		return ed25519.PrivateKey(block.Bytes), nil
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading identity key: %w", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating identity key: %w", err)
+	}
+
+	block := &pem.Block{Type: identityBlockType, Bytes: priv}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, fmt.Errorf("persisting identity key: %w", err)
+	}
+
+	return priv, nil
+}
+
+// Fingerprint returns a colon-separated hex fingerprint for an Ed25519 public key.
+func Fingerprint(pub ed25519.PublicKey) string {
+	return fingerprintBytes(pub)
+}
@@ -0,0 +1,101 @@
+// Package transport upgrades a wire.Session into an encrypted channel keyed
+// by a curve25519 handshake, so that the rest of the server can stay
+// oblivious to the fact that frame payloads are now ciphertext.
+package transport
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/SippChat/Sipp/pkg/wire"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Conn wraps a wire.Session and transparently seals/opens every frame's
+// payload with a per-session chacha20-poly1305 AEAD. Framing, and the
+// single persistent reader/writer underneath it, are Session's
+// responsibility; Conn only adds encryption on top.
+type Conn struct {
+	*wire.Session
+	aead cipher.AEAD
+
+	// Fingerprint is the connecting client's identity fingerprint, empty
+	// for connections where ClientHandshake has no local identity.
+	Fingerprint string
+	// Admin reports whether Fingerprint was present in the server's
+	// whitelist at handshake time.
+	Admin bool
+}
+
+func newConn(session *wire.Session, sharedSecret [32]byte) (*Conn, error) {
+	aead, err := chacha20poly1305.New(sharedSecret[:])
+	if err != nil {
+		return nil, fmt.Errorf("initializing cipher: %w", err)
+	}
+	return &Conn{Session: session, aead: aead}, nil
+}
+
+// WriteJSON seals v and sends it as a wire.MSG frame, the common case for
+// ordinary chat messages.
+func (c *Conn) WriteJSON(v interface{}) error {
+	return c.SendTyped(wire.MSG, v)
+}
+
+// ReadJSON reads the next frame, requires it to be a wire.MSG, and
+// unmarshals its opened payload into v.
+func (c *Conn) ReadJSON(v interface{}) error {
+	t, plaintext, err := c.RecvTyped()
+	if err != nil {
+		return err
+	}
+	if t != wire.MSG {
+		return fmt.Errorf("transport: expected a MSG frame, got type %d", t)
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+// SendTyped seals v (JSON-encoded; nil sends an empty payload) and frames
+// it as a t-typed message, e.g. wire.JOIN, wire.PING, or wire.BYE.
+func (c *Conn) SendTyped(t wire.Type, v interface{}) error {
+	var plaintext []byte
+	if v != nil {
+		var err error
+		plaintext, err = json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("marshalling message: %w", err)
+		}
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return c.Session.Send(wire.Frame{Type: t, Payload: sealed})
+}
+
+// RecvTyped reads and opens the next frame, returning its type and opened
+// payload for the caller to interpret: unmarshal it for a MSG, or just
+// branch on Type for a control frame like JOIN, PART, PING, PONG, or BYE.
+func (c *Conn) RecvTyped() (wire.Type, []byte, error) {
+	frame, err := c.Session.Recv()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(frame.Payload) < nonceSize {
+		return frame.Type, nil, io.ErrUnexpectedEOF
+	}
+	nonce, ciphertext := frame.Payload[:nonceSize], frame.Payload[nonceSize:]
+
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return frame.Type, nil, fmt.Errorf("opening message: %w", err)
+	}
+	return frame.Type, plaintext, nil
+}
@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// fingerprintBytes hashes raw key bytes into the colon-separated hex form
+// used throughout whitelist files and logs.
+func fingerprintBytes(key []byte) string {
+	sum := sha256.Sum256(key)
+	hexSum := hex.EncodeToString(sum[:])
+
+	var parts []string
+	for i := 0; i < len(hexSum); i += 2 {
+		parts = append(parts, hexSum[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// Whitelist is the set of client fingerprints granted admin privileges.
+// A missing or empty whitelist file simply means no client is an admin.
+type Whitelist struct {
+	fingerprints map[string]bool
+}
+
+// LoadWhitelist reads one fingerprint per line from path, ignoring blank
+// lines and lines starting with '#'. A non-existent file yields an empty
+// (not erroring) whitelist.
+func LoadWhitelist(path string) (*Whitelist, error) {
+	w := &Whitelist{fingerprints: make(map[string]bool)}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return w, nil
+		}
+		return nil, fmt.Errorf("opening whitelist: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		w.fingerprints[line] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading whitelist: %w", err)
+	}
+
+	return w, nil
+}
+
+// Allowed reports whether fingerprint is an admin.
+func (w *Whitelist) Allowed(fingerprint string) bool {
+	if w == nil {
+		return false
+	}
+	return w.fingerprints[fingerprint]
+}
@@ -0,0 +1,50 @@
+package wire
+
+import (
+	"bufio"
+	"net"
+)
+
+// Session owns the single persistent bufio.Reader/Writer pair for a
+// connection's entire lifetime, from the plaintext bootstrap (the
+// HandshakeReq/Res and curve25519 KEX lines) through the framed message
+// exchange that follows. Keeping exactly one buffered reader and writer
+// per connection, instead of each layer constructing its own, is what
+// fixes the old bug where an upgrade step could silently drop bytes the
+// previous bufio.Reader had already buffered.
+type Session struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// NewSession wraps conn with a Session ready for both line-based bootstrap
+// traffic and framed messages.
+func NewSession(conn net.Conn) *Session {
+	return &Session{Conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+}
+
+// ReadLine reads one newline-terminated line, for the plaintext bootstrap
+// that precedes framing.
+func (s *Session) ReadLine() (string, error) {
+	return s.r.ReadString('\n')
+}
+
+// WriteLine writes line and flushes. line is expected to already end in a
+// trailing newline.
+func (s *Session) WriteLine(line string) error {
+	if _, err := s.w.WriteString(line); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// Send writes f as a length-prefixed frame.
+func (s *Session) Send(f Frame) error {
+	return Encode(s.w, f)
+}
+
+// Recv reads the next length-prefixed frame.
+func (s *Session) Recv() (Frame, error) {
+	return Decode(s.r)
+}
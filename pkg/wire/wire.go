@@ -0,0 +1,87 @@
+// Package wire implements Sipp's framed wire protocol: every message is a
+// 4-byte big-endian length prefix, a 1-byte type, and a payload. This
+// replaces newline-delimited JSON, which breaks the moment a payload
+// contains a literal newline, and whose line-at-a-time reads made it easy
+// to accidentally construct a second bufio.Reader over the same
+// connection and silently drop whatever the first one had buffered.
+package wire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Type identifies what a Frame's payload holds.
+type Type uint8
+
+const (
+	HELLO Type = iota // handshake / key-exchange bootstrap
+	MSG               // a chat message
+	JOIN              // join a room
+	PART              // leave a room
+	PING              // keepalive request
+	PONG              // keepalive reply
+	BYE               // graceful disconnect
+)
+
+// MaxFrameSize bounds a frame's payload so a malicious or confused peer
+// can't make Decode allocate an unbounded buffer. The wire length prefix
+// itself runs one byte larger, since it also counts the type byte.
+const MaxFrameSize = 1 << 20 // 1 MiB
+
+// Frame is one length-prefixed unit of the wire protocol.
+type Frame struct {
+	Type    Type
+	Payload []byte
+}
+
+// frameWriter is the subset of *bufio.Writer Encode needs.
+type frameWriter interface {
+	Write(p []byte) (int, error)
+	Flush() error
+}
+
+// Encode writes f to w as a length-prefixed frame and flushes.
+func Encode(w frameWriter, f Frame) error {
+	if len(f.Payload) > MaxFrameSize {
+		return fmt.Errorf("wire: frame payload of %d bytes exceeds max %d", len(f.Payload), MaxFrameSize)
+	}
+
+	var header [5]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(f.Payload)+1))
+	header[4] = byte(f.Type)
+
+	if _, err := w.Write(header[:]); err != nil {
+		return fmt.Errorf("writing frame header: %w", err)
+	}
+	if len(f.Payload) > 0 {
+		if _, err := w.Write(f.Payload); err != nil {
+			return fmt.Errorf("writing frame payload: %w", err)
+		}
+	}
+	return w.Flush()
+}
+
+// Decode reads one length-prefixed frame from r.
+func Decode(r io.Reader) (Frame, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return Frame{}, err
+	}
+
+	n := binary.BigEndian.Uint32(lenBuf[:])
+	if n == 0 {
+		return Frame{}, fmt.Errorf("wire: frame has no type byte")
+	}
+	if n > MaxFrameSize+1 {
+		return Frame{}, fmt.Errorf("wire: frame length %d exceeds max %d", n, MaxFrameSize+1)
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return Frame{}, fmt.Errorf("reading frame body: %w", err)
+	}
+
+	return Frame{Type: Type(body[0]), Payload: body[1:]}, nil
+}
@@ -0,0 +1,76 @@
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func encodeDecode(t *testing.T, f Frame) (Frame, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	if err := Encode(w, f); err != nil {
+		return Frame{}, err
+	}
+	return Decode(&buf)
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	tests := []Frame{
+		{Type: HELLO, Payload: nil},
+		{Type: MSG, Payload: []byte("hello")},
+		{Type: JOIN, Payload: []byte("#sipp")},
+		{Type: BYE, Payload: []byte{}},
+	}
+
+	for _, f := range tests {
+		got, err := encodeDecode(t, f)
+		if err != nil {
+			t.Fatalf("round trip of %+v failed: %v", f, err)
+		}
+		if got.Type != f.Type {
+			t.Errorf("Type = %v, want %v", got.Type, f.Type)
+		}
+		if !bytes.Equal(got.Payload, f.Payload) {
+			t.Errorf("Payload = %q, want %q", got.Payload, f.Payload)
+		}
+	}
+}
+
+func TestEncodeRejectsOversizedPayload(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	f := Frame{Type: MSG, Payload: make([]byte, MaxFrameSize+1)}
+	if err := Encode(w, f); err == nil {
+		t.Fatal("Encode accepted a payload one byte over MaxFrameSize")
+	}
+}
+
+func TestMaxSizeFrameRoundTrips(t *testing.T) {
+	f := Frame{Type: MSG, Payload: make([]byte, MaxFrameSize)}
+	got, err := encodeDecode(t, f)
+	if err != nil {
+		t.Fatalf("a maximum-size frame failed to round trip: %v", err)
+	}
+	if len(got.Payload) != MaxFrameSize {
+		t.Errorf("Payload length = %d, want %d", len(got.Payload), MaxFrameSize)
+	}
+}
+
+func TestDecodeRejectsOversizedLength(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	// Hand-craft a frame header claiming more than the max allowed length,
+	// since Encode itself refuses to produce one.
+	f := Frame{Type: MSG, Payload: make([]byte, MaxFrameSize)}
+	if err := Encode(w, f); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	raw := buf.Bytes()
+	raw[0]++ // bump the length prefix's high byte past MaxFrameSize+1
+
+	if _, err := Decode(bytes.NewReader(raw)); err == nil {
+		t.Fatal("Decode accepted a frame length beyond MaxFrameSize+1")
+	}
+}
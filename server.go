@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"crypto/ed25519"
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
@@ -18,47 +19,77 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/SippChat/Sipp/pkg/history"
+	"github.com/SippChat/Sipp/pkg/protocol"
+	"github.com/SippChat/Sipp/pkg/room"
 	"github.com/SippChat/Sipp/pkg/straw"
+	"github.com/SippChat/Sipp/pkg/transport"
+	"github.com/SippChat/Sipp/pkg/wire"
 	"github.com/sirupsen/logrus"
 )
 
 const (
-	defaultPort   = 5199
-	expectedMagic = "SippClientHello"
-	invalidMsg    = "Invalid handshake"
-	logDir        = "logs"
-	motdFile      = "motd"
+	defaultPort       = 5199
+	defaultIRCPort    = 6667
+	invalidMsg        = "Invalid handshake"
+	logDir            = "logs"
+	motdFile          = "motd"
+	motdDir           = "motd.d"
+	defaultIdentity   = "host_key"
+	defaultAdminsFile = "admins"
+	defaultRoom       = "#sipp"
+	replayCount       = 50 // messages replayed to a client on join
+	historyFsync      = 5 * time.Second
+	defaultOutboxSize = 32 // buffered messages per client before enqueue starts dropping
+	defaultDebugPort  = 6060
+	writeTimeout      = 5 * time.Second // per-message deadline a client's outbox writer allows
 )
 
-type HandshakeReq struct {
-	Magic  string `json:"magic"`
-	Client string `json:"client"`
-}
-
-type HandshakeRes struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-}
+// HandshakeReq, HandshakeRes, and Message alias protocol's types so the
+// rest of this package can keep referring to them unqualified.
+type HandshakeReq = protocol.HandshakeReq
+type HandshakeRes = protocol.HandshakeRes
+type Message = protocol.Message
 
 var (
 	log          = logrus.New()
 	console      = logrus.New()
-	motd         string
-	clients      = make(map[net.Conn]string) // Map of connections to client IDs
-	clientsMutex = &sync.Mutex{}             // Mutex to protect client map
-	messageQueue = make(chan Message, 100)   // Channel for incoming messages
+	motd         string // MOTD with straw formatting tags applied, for native clients
+	plainMOTD    string // unformatted MOTD, for IRC clients
+	hostKey      ed25519.PrivateKey
+	admins       *transport.Whitelist
+	clients      = make(map[string]User)   // Map of client IDs to connected users
+	clientsMutex = &sync.RWMutex{}         // Mutex to protect client map
+	messageQueue = make(chan Message, 100) // Channel for incoming messages
+	rooms        = room.NewRegistry(motdDir)
+	historyStore history.Store
+	outboxSize   = defaultOutboxSize // messages buffered per client's outbox
 )
 
-type Message struct {
-	Sender   string `json:"sender"`   // Client ID of the sender
-	Receiver string `json:"receiver"` // Client ID of the receiver (can be empty for broadcast)
-	Content  string `json:"content"`  // Message content
+// User is a connected chat participant. Native Sipp clients and bridged IRC
+// clients both implement it so the rest of the server (clients map,
+// deliverToRoom, handleMessages) doesn't need to care which protocol a
+// given participant is speaking.
+type User interface {
+	// ID is the client ID this user is registered under in the clients map.
+	ID() string
+	// Deliver sends msg to this user in whatever wire format it expects.
+	Deliver(msg Message) error
 }
 
 func main() {
 	port := flag.Int("p", defaultPort, "Port to bind to")
+	ircPort := flag.Int("irc-port", defaultIRCPort, "Port to bind the IRC-compatible listener to")
+	identityPath := flag.String("identity", defaultIdentity, "Path to the server's Ed25519 host key")
+	adminsPath := flag.String("admins", defaultAdminsFile, "Path to the admin fingerprint whitelist")
+	historyBackend := flag.String("history", "ring", "Scrollback backend: \"ring\" (in-memory) or \"file\" (logs/<room>.jsonl)")
+	historySize := flag.Int("history-size", history.DefaultSize, "Messages kept per room by the ring history backend")
+	outboxSizeFlag := flag.Int("outbox-size", defaultOutboxSize, "Messages buffered per client before its outbox starts dropping")
+	debugPort := flag.Int("debug-port", defaultDebugPort, "Port to expose fan-out metrics on at /debug (0 disables)")
 	flag.Parse()
 
+	outboxSize = *outboxSizeFlag
+
 	initMOTD()
 	handleSignals()
 
@@ -67,9 +98,15 @@ func main() {
 
 	logAndConsole("Sipp server starting up...")
 
+	initIdentity(*identityPath, *adminsPath)
+	initHistory(*historyBackend, *historySize)
+
 	// Start message handler
 	go handleMessages()
 
+	go startIRCServer(*ircPort)
+	go startDebugServer(*debugPort)
+
 	startServer(*port)
 }
 
@@ -77,14 +114,46 @@ func main() {
 func initMOTD() {
 	if _, err := os.Stat(motdFile); err == nil {
 		var err error
-		motd, err = readFile(motdFile)
+		plainMOTD, err = readFile(motdFile)
 		if err != nil {
 			log.Fatalf("Error reading MOTD: %v", err)
 		}
 	} else {
-		motd = ""
+		plainMOTD = ""
+	}
+	motd = serialize(plainMOTD)
+}
+
+// initIdentity loads (or generates, on first run) the server's host key and
+// loads the admin fingerprint whitelist.
+func initIdentity(identityPath, adminsPath string) {
+	var err error
+	hostKey, err = transport.LoadOrGenerateIdentity(identityPath)
+	if err != nil {
+		log.Fatalf("Error loading host key: %v", err)
+	}
+	logAndConsole(fmt.Sprintf("Host key fingerprint: %s", transport.Fingerprint(hostKey.Public().(ed25519.PublicKey))))
+
+	admins, err = transport.LoadWhitelist(adminsPath)
+	if err != nil {
+		log.Fatalf("Error loading admin whitelist: %v", err)
+	}
+}
+
+// initHistory sets up the scrollback backend.
+func initHistory(backend string, ringSize int) {
+	switch backend {
+	case "ring":
+		historyStore = history.NewRingStore(ringSize)
+	case "file":
+		store, err := history.NewFileStore(logDir, historyFsync)
+		if err != nil {
+			log.Fatalf("Error opening history store: %v", err)
+		}
+		historyStore = store
+	default:
+		log.Fatalf("Unknown history backend: %s", backend)
 	}
-	motd = serialize(motd)
 }
 
 // handleSignals sets up signal handling for graceful shutdown.
@@ -164,82 +233,139 @@ func startServer(port int) {
 func handleConn(conn net.Conn) {
 	defer conn.Close()
 
-	// Perform handshake
-	if err := processHandshake(conn); err != nil {
+	// Perform the plaintext handshake, then upgrade to an encrypted Conn.
+	sealed, err := processHandshake(conn)
+	if err != nil {
 		log.Errorf("Handshake failed: %v", err)
 		return
 	}
+	if sealed == nil {
+		// Magic/client check failed; sendResponse already told the client.
+		return
+	}
 
 	// Register client
 	clientID := conn.RemoteAddr().String()
-	addClient(conn, clientID)
-	defer removeClient(conn)
-
-	// Handle incoming client messages
+	if sealed.Admin {
+		logAndConsole(fmt.Sprintf("Client %s authenticated as admin (%s)", clientID, sealed.Fingerprint))
+	}
+	user := &nativeUser{id: clientID, conn: sealed, currentRoom: defaultRoom}
+	user.out = newOutbox(user.writeNow)
+	defer user.out.close()
+	addClient(user)
+	joinRoom(user, defaultRoom)
+	defer rooms.LeaveAll(clientID)
+	defer removeClient(clientID)
+
+	// Handle incoming client frames
 	for {
-		message, err := readMessage(conn)
-		if err != nil {
-			if err != io.EOF {
-				log.Errorf("Error reading message: %v", err)
-			}
+		if !handleFrame(user, sealed) {
 			return
 		}
+	}
+}
+
+// handleFrame reads and dispatches one frame from sealed, returning false
+// once the connection should be torn down (read error or a BYE frame).
+func handleFrame(user *nativeUser, sealed *transport.Conn) bool {
+	frameType, payload, err := sealed.RecvTyped()
+	if err != nil {
+		if err != io.EOF {
+			log.Errorf("Error reading message: %v", err)
+		}
+		return false
+	}
 
-		// Send message to the queue
+	switch frameType {
+	case wire.MSG:
+		var msg Message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			log.Errorf("Error parsing message from %s: %v", user.id, err)
+			return true
+		}
+		if dispatchCommand(user, msg.Content) {
+			return true
+		}
 		messageQueue <- Message{
-			Sender:  clientID,
-			Content: message,
+			Sender:  user.id,
+			Channel: user.currentRoom,
+			Content: msg.Content,
+		}
+	case wire.JOIN:
+		cmdJoin(user, roomArgs(payload))
+	case wire.PART:
+		cmdPart(user, roomArgs(payload))
+	case wire.PING:
+		if err := sealed.SendTyped(wire.PONG, nil); err != nil {
+			log.Errorf("Error sending pong to %s: %v", user.id, err)
 		}
+	case wire.PONG:
+		// keepalive ack, nothing to do
+	case wire.BYE:
+		return false
+	default:
+		log.Errorf("Unknown frame type %d from %s", frameType, user.id)
 	}
+
+	return true
 }
 
-// processHandshake handles the client handshake and responds accordingly.
-func processHandshake(conn net.Conn) error {
-	reader := bufio.NewReader(conn)
-	writer := bufio.NewWriter(conn)
+// roomArgs turns a JOIN/PART frame's payload into the args slice cmdJoin
+// and cmdPart expect: the named room, or none if the client left it blank
+// (cmdPart falls back to the user's current room in that case).
+func roomArgs(payload []byte) []string {
+	if len(payload) == 0 {
+		return nil
+	}
+	return []string{string(payload)}
+}
 
-	raw, err := reader.ReadString('\n')
+// processHandshake validates the client's plaintext HandshakeReq and, once
+// accepted, upgrades conn to an encrypted transport.Conn via the curve25519
+// key exchange. Both steps share a single wire.Session, so no bytes the
+// HandshakeReq read buffers ahead of time can be lost when the key exchange
+// takes over. It returns a nil Conn (with a nil error) if the handshake was
+// rejected.
+func processHandshake(conn net.Conn) (*transport.Conn, error) {
+	session := wire.NewSession(conn)
+
+	raw, err := session.ReadLine()
 	if err != nil {
-		return fmt.Errorf("reading handshake: %w", err)
+		return nil, fmt.Errorf("reading handshake: %w", err)
 	}
 
 	var req HandshakeReq
 	if err := json.Unmarshal([]byte(raw), &req); err != nil {
-		return fmt.Errorf("parsing handshake: %w", err)
+		return nil, fmt.Errorf("parsing handshake: %w", err)
 	}
 
-	if req.Magic != expectedMagic || req.Client == "" {
-		if err := sendResponse(writer, false, invalidMsg); err != nil {
+	if req.Magic != protocol.Magic || req.Client == "" {
+		if err := sendResponse(session, false, invalidMsg); err != nil {
 			log.Errorf("Sending invalid handshake response failed: %v", err)
 		}
-		return nil
+		return nil, nil
 	}
 
-	if err := sendResponse(writer, true, motd); err != nil {
+	if err := sendResponse(session, true, motd); err != nil {
 		log.Errorf("Sending valid handshake response failed: %v", err)
 	}
 
-	return nil
+	sealed, err := transport.ServerHandshake(session, hostKey, admins)
+	if err != nil {
+		return nil, fmt.Errorf("key exchange: %w", err)
+	}
+
+	return sealed, nil
 }
 
 // sendResponse sends a handshake response to the client.
-func sendResponse(writer *bufio.Writer, success bool, message string) error {
+func sendResponse(session *wire.Session, success bool, message string) error {
 	res := HandshakeRes{Success: success, Message: serialize(message)}
-	return writeMessage(writer, res)
-}
-
-// writeMessage serializes and sends a message to the client.
-func writeMessage(writer *bufio.Writer, message interface{}) error {
-	msgJSON, err := json.Marshal(message)
+	raw, err := json.Marshal(res)
 	if err != nil {
 		return fmt.Errorf("marshalling message: %w", err)
 	}
-
-	if _, err := writer.WriteString(string(msgJSON) + "\n"); err != nil {
-		return fmt.Errorf("sending message: %w", err)
-	}
-
-	return writer.Flush()
+	return session.WriteLine(string(raw) + "\n")
 }
 
 // readFile reads the contents of a file into a string.
@@ -273,81 +399,149 @@ func serialize(message string) string {
 }
 
 // addClient adds a new client to the client map.
-func addClient(conn net.Conn, clientID string) {
+func addClient(user User) {
 	clientsMutex.Lock()
 	defer clientsMutex.Unlock()
-	clients[conn] = clientID
-	logAndConsole(fmt.Sprintf("Client %s connected", clientID))
+	clients[user.ID()] = user
+	logAndConsole(fmt.Sprintf("Client %s connected", user.ID()))
+}
+
+// broadcastRecipients takes a read-lock just long enough to snapshot which
+// of ids are currently connected, so callers can enqueue to them afterwards
+// without holding clientsMutex across any (potentially slow) delivery.
+func broadcastRecipients(ids []string) []User {
+	clientsMutex.RLock()
+	defer clientsMutex.RUnlock()
+
+	recipients := make([]User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := clients[id]; ok {
+			recipients = append(recipients, user)
+		}
+	}
+	return recipients
 }
 
 // removeClient removes a client from the client map.
-func removeClient(conn net.Conn) {
+func removeClient(clientID string) {
 	clientsMutex.Lock()
 	defer clientsMutex.Unlock()
-	if clientID, ok := clients[conn]; ok {
-		delete(clients, conn)
+	if _, ok := clients[clientID]; ok {
+		delete(clients, clientID)
 		logAndConsole(fmt.Sprintf("Client %s disconnected", clientID))
 	}
 }
 
-// broadcastMessage sends a message to all clients except the sender.
-func broadcastMessage(senderID, content string) {
-	clientsMutex.Lock()
-	defer clientsMutex.Unlock()
-	for conn, clientID := range clients {
-		if clientID != senderID {
-			if err := sendMessage(conn, Message{
-				Sender:  senderID,
-				Content: content,
-			}); err != nil {
-				log.Errorf("Error sending broadcast message to %s: %v", clientID, err)
-			}
+// deliverToRoom fans msg out to every member of msg.Channel except its
+// sender. Recipients are snapshotted under a read-lock and then enqueued to
+// outside it, so one slow client's outbox filling up can't make every other
+// member wait.
+func deliverToRoom(msg Message) {
+	r, ok := rooms.Get(msg.Channel)
+	if !ok {
+		return
+	}
+	if err := historyStore.Append(history.Message{
+		Room:      msg.Channel,
+		Sender:    msg.Sender,
+		Content:   msg.Content,
+		Timestamp: time.Now(),
+	}); err != nil {
+		log.Errorf("Error recording history for %s: %v", msg.Channel, err)
+	}
+
+	for _, user := range broadcastRecipients(r.MemberIDs()) {
+		if user.ID() == msg.Sender {
+			continue
+		}
+		if err := user.Deliver(Message{Sender: msg.Sender, Channel: msg.Channel, Content: msg.Content}); err != nil {
+			log.Errorf("Error sending message to %s: %v", user.ID(), err)
 		}
 	}
 }
 
-// sendMessage sends a message to a specific client.
-func sendMessage(conn net.Conn, message Message) error {
-	return writeMessage(bufio.NewWriter(conn), message)
+// deliverDirect sends msg to its single named receiver.
+func deliverDirect(msg Message) {
+	clientsMutex.RLock()
+	user, ok := clients[msg.Receiver]
+	clientsMutex.RUnlock()
+	if !ok {
+		return
+	}
+	if err := user.Deliver(Message{Sender: msg.Sender, Content: msg.Content}); err != nil {
+		log.Errorf("Error sending message to %s: %v", msg.Receiver, err)
+	}
 }
 
-// handleMessages processes messages from the queue and routes them.
+// handleMessages processes messages from the queue and routes them by
+// receiver (a DM) or, failing that, by channel membership.
 func handleMessages() {
 	for msg := range messageQueue {
-		if msg.Receiver == "" { // Broadcast message
-			broadcastMessage(msg.Sender, msg.Content)
-		} else { // Send to specific client
-			clientsMutex.Lock()
-			defer clientsMutex.Unlock()
-			for conn, id := range clients {
-				if id == msg.Receiver {
-					if err := sendMessage(conn, Message{
-						Sender:  msg.Sender,
-						Content: msg.Content,
-					}); err != nil {
-						log.Errorf("Error sending message to %s: %v", id, err)
-					}
-					break
-				}
-			}
+		switch {
+		case msg.Receiver != "":
+			deliverDirect(msg)
+		case msg.Channel != "":
+			deliverToRoom(msg)
 		}
 	}
 }
 
-// readMessage reads a message from the client.
-func readMessage(conn net.Conn) (string, error) {
-	reader := bufio.NewReader(conn)
-	raw, err := reader.ReadString('\n')
-	if err != nil {
-		return "", err
+// joinRoom adds user to the named room, creating it if necessary, delivers
+// its MOTD (if it has one), and replays its recent scrollback.
+func joinRoom(user *nativeUser, name string) {
+	r := rooms.GetOrCreate(name)
+	r.Join(user.id)
+	if r.MOTD != "" {
+		if err := user.Deliver(Message{Sender: serverName, Channel: name, Content: serialize(r.MOTD)}); err != nil {
+			log.Errorf("Error sending room MOTD to %s: %v", user.id, err)
+		}
 	}
+	replayHistory(user, name)
+}
 
-	var msg Message
-	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
-		return "", fmt.Errorf("parsing message: %w", err)
+// replayHistory delivers the last replayCount messages of room to user, so
+// it doesn't miss everything sent while it was offline or not yet joined.
+func replayHistory(user User, name string) {
+	backlog, err := historyStore.Tail(name, replayCount)
+	if err != nil {
+		log.Errorf("Error reading history for %s: %v", name, err)
+		return
 	}
+	for _, entry := range backlog {
+		if err := user.Deliver(Message{Sender: entry.Sender, Channel: name, Content: entry.Content}); err != nil {
+			log.Errorf("Error replaying history to %s: %v", user.ID(), err)
+		}
+	}
+}
+
+// nativeUser is a client speaking Sipp's own encrypted JSON protocol.
+type nativeUser struct {
+	id          string
+	conn        *transport.Conn
+	currentRoom string // room new messages from this client are tagged with
+	out         *outbox
+}
+
+func (u *nativeUser) ID() string { return u.id }
 
-	return msg.Content, nil
+// Deliver enqueues msg on u's outbox; the drain goroutine started in
+// handleConn does the actual (deadlined) wire write.
+func (u *nativeUser) Deliver(msg Message) error {
+	return u.out.enqueue(msg)
+}
+
+// writeNow performs the blocking write of msg to the wire, bounded by
+// writeTimeout so a stuck client gets dropped instead of stalling its
+// outbox drain goroutine forever. A failed write closes the connection,
+// which ends handleConn's read loop and unregisters the client.
+func (u *nativeUser) writeNow(msg Message) error {
+	u.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	defer u.conn.SetWriteDeadline(time.Time{})
+	if err := u.conn.WriteJSON(msg); err != nil {
+		u.conn.Close()
+		return err
+	}
+	return nil
 }
 
 // logAndConsole logs and prints messages to both log and console.